@@ -0,0 +1,376 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is returned by a Cache implementation when the requested key
+// does not exist.
+var ErrCacheMiss = errors.New("dstack: certificate cache miss")
+
+// Cache is used by CertManager to persist previously obtained certificates
+// across process restarts. Implementations should not rely on the key naming
+// pattern, but keys are always composed of printable ASCII characters.
+type Cache interface {
+	// Get returns the cached data for the specified key, or ErrCacheMiss if
+	// there is none.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores data under the specified key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Delete removes the data stored under the specified key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// DefaultRenewBefore is how long before a certificate's NotAfter CertManager
+// requests a replacement, matching autocert's default.
+const DefaultRenewBefore = 30 * 24 * time.Hour
+
+// SNIOptions lets a caller override the GetTlsKey parameters used for a
+// particular SNI host. Fields are merged individually against the Manager
+// defaults (Subject: host, AltNames: none, UsageRaTls/UsageServerAuth: true,
+// UsageClientAuth: false): a zero Subject or nil AltNames/usage pointer
+// leaves the corresponding default in place, so callers only need to set
+// the fields they want to change.
+type SNIOptions struct {
+	Subject         string
+	AltNames        []string
+	UsageRaTls      *bool
+	UsageServerAuth *bool
+	UsageClientAuth *bool
+}
+
+// Bool returns a pointer to b, for use with SNIOptions' usage fields.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// CertManager obtains and caches RA-TLS certificates from a DstackClient,
+// analogous to golang.org/x/crypto/acme/autocert.Manager, and renews them in
+// the background before they expire.
+type CertManager struct {
+	// Client is used to request TLS keys from the dstack agent. If nil, a
+	// client is created with NewDstackClient() the first time it's needed.
+	Client *DstackClient
+
+	// HostPolicy controls which SNI host names the Manager will issue
+	// certificates for. If nil, all hosts are allowed.
+	HostPolicy HostPolicy
+
+	// SNIOverrides optionally customizes the GetTlsKey request made for a
+	// given host, keyed by exact host name.
+	SNIOverrides map[string]SNIOptions
+
+	// Cache optionally persists certificates across restarts. If nil,
+	// certificates are only kept in memory for the lifetime of the Manager.
+	Cache Cache
+
+	// RenewBefore specifies how early a certificate is replaced before it
+	// expires. If zero, DefaultRenewBefore is used.
+	RenewBefore time.Duration
+
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+	renew map[string]context.CancelFunc
+}
+
+// HostPolicy reports whether the Manager is allowed to serve the given SNI
+// host name. It returns a non-nil error if the host should be rejected.
+type HostPolicy func(ctx context.Context, host string) error
+
+// HostWhitelist returns a HostPolicy that only allows the given host names,
+// matched exactly.
+func HostWhitelist(hosts ...string) HostPolicy {
+	allowed := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		allowed[h] = true
+	}
+	return func(_ context.Context, host string) error {
+		if !allowed[host] {
+			return fmt.Errorf("dstack: host %q not allowed by whitelist", host)
+		}
+		return nil
+	}
+}
+
+// client returns the configured DstackClient, creating a default one lazily.
+func (m *CertManager) client() *DstackClient {
+	if m.Client == nil {
+		m.Client = NewDstackClient()
+	}
+	return m.Client
+}
+
+// renewBefore returns the configured renewal window, or DefaultRenewBefore.
+func (m *CertManager) renewBefore() time.Duration {
+	if m.RenewBefore > 0 {
+		return m.RenewBefore
+	}
+	return DefaultRenewBefore
+}
+
+// TLSConfig returns a *tls.Config that serves RA-TLS certificates obtained
+// on demand via GetCertificate. Callers can further customize the returned
+// config, e.g. to set NextProtos.
+func (m *CertManager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
+
+// Listener returns a net.Listener that accepts TLS connections on the given
+// address, serving certificates obtained through this Manager.
+func (m *CertManager) Listener(address string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewListener(ln, m.TLSConfig()), nil
+}
+
+// HTTPHandler returns a handler that falls back to fallback for any request
+// that is not part of the TLS handshake. Since dstack's RA-TLS keys are
+// issued directly (no HTTP-01 challenge), this simply returns fallback,
+// defaulting to a handler that redirects to https.
+func (m *CertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	if fallback != nil {
+		return fallback
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := "https://" + r.Host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+// GetCertificate implements the tls.Config.GetCertificate hook. It returns a
+// cached certificate for hello.ServerName, requesting and caching a new one
+// via GetTlsKey if necessary.
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	ctx := context.Background()
+	host := hello.ServerName
+	if host == "" {
+		return nil, errors.New("dstack: missing server name (SNI)")
+	}
+
+	if m.HostPolicy != nil {
+		if err := m.HostPolicy(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+
+	if cert := m.cachedCert(host); cert != nil {
+		return cert, nil
+	}
+
+	if cert := m.diskCert(ctx, host); cert != nil {
+		m.storeCert(host, cert)
+		m.scheduleRenewal(host, cert.Leaf.NotAfter)
+		return cert, nil
+	}
+
+	cert, notAfter, err := m.requestCert(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	m.storeCert(host, cert)
+	m.scheduleRenewal(host, notAfter)
+	return cert, nil
+}
+
+// cachedCert returns the in-memory certificate for host, if any and not
+// expired. An expired entry is evicted so callers fall through to diskCert
+// or requestCert instead of serving it forever.
+func (m *CertManager) cachedCert(host string) *tls.Certificate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cert, ok := m.certs[host]
+	if !ok {
+		return nil
+	}
+	if !time.Now().Before(cert.Leaf.NotAfter) {
+		delete(m.certs, host)
+		return nil
+	}
+	return cert
+}
+
+// diskCert returns host's certificate from Cache, if present, not expired,
+// and still within the renewal window.
+func (m *CertManager) diskCert(ctx context.Context, host string) *tls.Certificate {
+	if m.Cache == nil {
+		return nil
+	}
+	data, err := m.Cache.Get(ctx, cacheKey(host))
+	if err != nil {
+		return nil
+	}
+	cert, err := decodeCertificate(data)
+	if err != nil {
+		return nil
+	}
+	if time.Until(cert.Leaf.NotAfter) <= m.renewBefore() {
+		return nil
+	}
+	return cert
+}
+
+// storeCert saves cert in memory and, if Cache is set, on disk.
+func (m *CertManager) storeCert(host string, cert *tls.Certificate) {
+	m.mu.Lock()
+	if m.certs == nil {
+		m.certs = make(map[string]*tls.Certificate)
+	}
+	m.certs[host] = cert
+	m.mu.Unlock()
+
+	if m.Cache == nil {
+		return
+	}
+	if data, err := encodeCertificate(cert); err == nil {
+		_ = m.Cache.Put(context.Background(), cacheKey(host), data)
+	}
+}
+
+// requestCert obtains a fresh RA-TLS key/certificate for host and returns
+// the parsed tls.Certificate along with its leaf's NotAfter.
+func (m *CertManager) requestCert(ctx context.Context, host string) (*tls.Certificate, time.Time, error) {
+	subject := host
+	var altNames []string
+	usageRaTls := true
+	usageServerAuth := true
+	usageClientAuth := false
+
+	if o, ok := m.SNIOverrides[host]; ok {
+		subject = firstNonEmpty(o.Subject, host)
+		if o.AltNames != nil {
+			altNames = o.AltNames
+		}
+		if o.UsageRaTls != nil {
+			usageRaTls = *o.UsageRaTls
+		}
+		if o.UsageServerAuth != nil {
+			usageServerAuth = *o.UsageServerAuth
+		}
+		if o.UsageClientAuth != nil {
+			usageClientAuth = *o.UsageClientAuth
+		}
+	}
+
+	opts := []TlsKeyOption{
+		WithSubject(subject),
+		WithUsageRaTls(usageRaTls),
+		WithUsageServerAuth(usageServerAuth),
+		WithUsageClientAuth(usageClientAuth),
+	}
+	if len(altNames) > 0 {
+		opts = append(opts, WithAltNames(altNames))
+	}
+
+	resp, err := m.client().GetTlsKey(ctx, opts...)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("dstack: failed to get TLS key for %q: %w", host, err)
+	}
+
+	cert, err := tlsCertificateFromPEM(resp.Key, resp.CertificateChain)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	notAfter := cert.Leaf.NotAfter
+	return cert, notAfter, nil
+}
+
+// renewalRetryInitialInterval and renewalRetryMaxInterval bound the backoff
+// used to retry a failed background renewal, so a transient error (e.g. the
+// dstack agent being briefly unreachable) doesn't permanently stop renewal.
+const (
+	renewalRetryInitialInterval = time.Minute
+	renewalRetryMaxInterval     = time.Hour
+)
+
+// scheduleRenewal arranges for host's certificate to be refreshed in the
+// background renewBefore its expiry. Any previously scheduled renewal for
+// host is canceled first.
+func (m *CertManager) scheduleRenewal(host string, notAfter time.Time) {
+	m.mu.Lock()
+	if cancel, ok := m.renew[host]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if m.renew == nil {
+		m.renew = make(map[string]context.CancelFunc)
+	}
+	m.renew[host] = cancel
+	m.mu.Unlock()
+
+	delay := time.Until(notAfter) - m.renewBefore()
+	if delay < 0 {
+		delay = 0
+	}
+
+	go m.renewLoop(ctx, host, notAfter, delay)
+}
+
+// renewLoop waits delay, then requests a fresh certificate for host,
+// retrying with capped exponential backoff on failure. It keeps retrying
+// until it succeeds (rescheduling the next renewal) or notAfter is reached,
+// at which point it gives up and lets GetCertificate fetch a fresh
+// certificate synchronously, since cachedCert no longer serves expired
+// certificates.
+func (m *CertManager) renewLoop(ctx context.Context, host string, notAfter time.Time, delay time.Duration) {
+	backoff := renewalRetryInitialInterval
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		cert, newNotAfter, err := m.requestCert(ctx, host)
+		if err != nil {
+			if !time.Now().Before(notAfter) {
+				return
+			}
+			delay = backoff
+			if backoff < renewalRetryMaxInterval {
+				backoff *= 2
+				if backoff > renewalRetryMaxInterval {
+					backoff = renewalRetryMaxInterval
+				}
+			}
+			continue
+		}
+
+		m.storeCert(host, cert)
+		m.scheduleRenewal(host, newNotAfter)
+		return
+	}
+}
+
+// cacheKey returns the Cache key used to store host's certificate.
+func cacheKey(host string) string {
+	return host + "+cert"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// certPEMBlockType and keyPEMBlockType label the blocks written by
+// encodeCertificate, in the order leaf/intermediates/key.
+const keyPEMBlockType = "PRIVATE KEY"
+
+// tlsCertificateFromPEM builds a tls.Certificate from a PEM-encoded private
+// key, as returned in GetTlsKeyResponse.Key, and the PEM-encoded certificate
+// chain returned in GetTlsKeyResponse.CertificateChain (leaf first).
+func tlsCertificateFromPEM(keyPEM string, chainPEM []string) (*tls.Certificate, error) {
+	if len(chainPEM) == 0 {
+		return nil, fmt.Errorf("dstack: certificate chain is empty")
+	}
+
+	certPEM := make([]byte, 0)
+	for _, c := range chainPEM {
+		certPEM = append(certPEM, []byte(c)...)
+		certPEM = append(certPEM, '\n')
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to parse TLS certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to parse leaf certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	return &cert, nil
+}
+
+// encodeCertificate re-serializes a tls.Certificate as concatenated PEM
+// certificate blocks (leaf first) followed by a PEM-encoded PKCS#8 private
+// key, suitable for storage in a Cache and later round-tripping through
+// decodeCertificate.
+func encodeCertificate(cert *tls.Certificate) ([]byte, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to marshal private key: %w", err)
+	}
+
+	var out []byte
+	for _, der := range cert.Certificate {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	out = append(out, pem.EncodeToMemory(&pem.Block{Type: keyPEMBlockType, Bytes: keyDER})...)
+	return out, nil
+}
+
+// decodeCertificate parses the PEM data produced by encodeCertificate back
+// into a tls.Certificate with Leaf populated.
+func decodeCertificate(data []byte) (*tls.Certificate, error) {
+	var certDER [][]byte
+	var keyDER []byte
+
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			certDER = append(certDER, block.Bytes)
+		case keyPEMBlockType:
+			keyDER = block.Bytes
+		}
+	}
+
+	if len(certDER) == 0 || keyDER == nil {
+		return nil, fmt.Errorf("dstack: cached certificate data is incomplete")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to parse cached private key: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(certDER[0])
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to parse cached leaf certificate: %w", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: certDER,
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
@@ -19,6 +19,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Represents the response from a TLS key derivation request.
@@ -157,6 +159,8 @@ func (r *GetQuoteResponse) ReplayRTMRs() (map[int]string, error) {
 type QuoteHashAlgorithm string
 
 const (
+	// SHA384 hash algorithm
+	SHA384 QuoteHashAlgorithm = "sha384"
 	// SHA512 hash algorithm
 	SHA512 QuoteHashAlgorithm = "sha512"
 	// RAW means no hashing, just use the raw bytes
@@ -169,6 +173,12 @@ type DstackClient struct {
 	baseURL    string
 	httpClient *http.Client
 	logger     *slog.Logger
+
+	retryPolicy   RetryPolicy
+	requestIDFunc func() string
+	transportOpt  http.RoundTripper
+	breakers      map[string]*circuitBreaker
+	breakersMu    sync.Mutex
 }
 
 // Functional option for configuring a DstackClient.
@@ -188,16 +198,44 @@ func WithLogger(logger *slog.Logger) DstackClientOption {
 	}
 }
 
+// WithRetryPolicy sets the backoff and circuit-breaker policy used by
+// sendRPCRequest. If not set, DefaultRetryPolicy() is used.
+func WithRetryPolicy(policy RetryPolicy) DstackClientOption {
+	return func(c *DstackClient) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithHTTPTransport sets the http.RoundTripper used for requests over
+// http(s) endpoints. It has no effect when talking to a unix socket
+// endpoint, which always dials the socket directly.
+func WithHTTPTransport(transport http.RoundTripper) DstackClientOption {
+	return func(c *DstackClient) {
+		c.transportOpt = transport
+	}
+}
+
+// WithRequestIDFunc sets the function used to generate the X-Request-Id
+// header attached to every RPC request, for correlating client and server
+// logs. If not set, requests are sent without a request-id header.
+func WithRequestIDFunc(f func() string) DstackClientOption {
+	return func(c *DstackClient) {
+		c.requestIDFunc = f
+	}
+}
+
 // Creates a new DstackClient instance based on the provided endpoint.
 // If the endpoint is empty, it will use the simulator endpoint if it is
 // set in the environment through DSTACK_SIMULATOR_ENDPOINT. Otherwise, it
 // will use the default endpoint at /var/run/dstack.sock.
 func NewDstackClient(opts ...DstackClientOption) *DstackClient {
 	client := &DstackClient{
-		endpoint:   "",
-		baseURL:    "",
-		httpClient: &http.Client{},
-		logger:     slog.Default(),
+		endpoint:    "",
+		baseURL:     "",
+		httpClient:  &http.Client{},
+		logger:      slog.Default(),
+		retryPolicy: DefaultRetryPolicy(),
+		breakers:    make(map[string]*circuitBreaker),
 	}
 
 	for _, opt := range opts {
@@ -208,6 +246,9 @@ func NewDstackClient(opts ...DstackClientOption) *DstackClient {
 
 	if strings.HasPrefix(client.endpoint, "http://") || strings.HasPrefix(client.endpoint, "https://") {
 		client.baseURL = client.endpoint
+		if client.transportOpt != nil {
+			client.httpClient.Transport = client.transportOpt
+		}
 	} else {
 		client.baseURL = "http://localhost"
 		client.httpClient = &http.Client{
@@ -222,6 +263,19 @@ func NewDstackClient(opts ...DstackClientOption) *DstackClient {
 	return client
 }
 
+// breakerFor returns the circuit breaker tracking path, creating one if
+// necessary.
+func (c *DstackClient) breakerFor(path string) *circuitBreaker {
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	b, ok := c.breakers[path]
+	if !ok {
+		b = newCircuitBreaker(c.retryPolicy.BreakerThreshold, c.retryPolicy.BreakerCooldown)
+		c.breakers[path] = b
+	}
+	return b
+}
+
 // Returns the appropriate endpoint based on environment and input. If the
 // endpoint is empty, it will use the simulator endpoint if it is set in the
 // environment through DSTACK_SIMULATOR_ENDPOINT. Otherwise, it will use the
@@ -237,35 +291,105 @@ func (c *DstackClient) getEndpoint() string {
 	return "/var/run/dstack.sock"
 }
 
-// Sends an RPC request to the dstack service.
+// Sends an RPC request to the dstack service, retrying transient failures
+// with exponential backoff and jitter, and failing fast via a per-endpoint
+// circuit breaker once path has seen too many consecutive errors. Retries
+// stop once ctx is done or the retry policy's MaxElapsedTime has elapsed,
+// whichever comes first.
 func (c *DstackClient) sendRPCRequest(ctx context.Context, path string, payload interface{}) ([]byte, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
 
+	breaker := c.breakerFor(path)
+	if !breaker.Allow() {
+		return nil, fmt.Errorf("dstack: circuit breaker open for %s", path)
+	}
+
+	requestID := ""
+	if c.requestIDFunc != nil {
+		requestID = c.requestIDFunc()
+	}
+
+	policy := c.retryPolicy
+	start := time.Now()
+	backoff := policy.InitialInterval
+
+	for attempt := 0; ; attempt++ {
+		body, status, reqErr := c.doRequest(ctx, path, jsonData, requestID)
+
+		if reqErr == nil && status == http.StatusOK {
+			breaker.RecordSuccess()
+			return body, nil
+		}
+
+		breaker.RecordFailure()
+
+		var err error
+		if reqErr != nil {
+			err = reqErr
+		} else {
+			err = fmt.Errorf("unexpected status code: %d, body: %s", status, body)
+		}
+
+		if !isRetryableStatus(status) && reqErr == nil {
+			return nil, err
+		}
+		if attempt >= policy.MaxRetries || time.Since(start) >= policy.MaxElapsedTime {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(withJitter(backoff)):
+		}
+
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if backoff > policy.MaxInterval {
+			backoff = policy.MaxInterval
+		}
+	}
+}
+
+// doRequest performs a single POST to path and returns the response body,
+// status code, and any transport-level error. It logs the outcome via
+// c.logger.
+func (c *DstackClient) doRequest(ctx context.Context, path string, jsonData []byte, requestID string) ([]byte, int, error) {
+	start := time.Now()
+
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-
 	req.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		req.Header.Set("X-Request-Id", requestID)
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		c.logger.Warn("dstack rpc request failed", "path", path, "request_id", requestID, "latency", time.Since(start), "error", err)
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		c.logger.Warn("dstack rpc response read failed", "path", path, "request_id", requestID, "latency", time.Since(start), "error", err)
+		return nil, resp.StatusCode, err
 	}
-	return body, nil
+
+	c.logger.Debug("dstack rpc request", "path", path, "request_id", requestID, "latency", time.Since(start), "status", resp.StatusCode)
+	return body, resp.StatusCode, nil
+}
+
+// isRetryableStatus reports whether a response status code warrants a
+// retry. Only server errors are retried; 4xx responses are not, since a
+// retry would reproduce the same error.
+func isRetryableStatus(status int) bool {
+	return status >= 500
 }
 
 // TlsKeyOption defines a function type for TLS key options
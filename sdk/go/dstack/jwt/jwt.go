@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jwt issues and verifies compact JWS tokens signed by a
+// dstack.Signer, carrying the key's SignatureChain and, optionally, a TDX
+// quote in a custom header so a relying party can accept an attested JWT
+// without contacting a separate KMS.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Dstack-TEE/dstack/sdk/go/dstack"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// Header is the JWS protected header used by Issue. X5C mirrors the
+// x5c ("X.509 certificate chain") convention, but carries a dstack
+// SignatureChain and TDX quote instead of certificates, since dstack keys
+// are attested rather than CA-issued.
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	X5C *X5C   `json:"x5c,omitempty"`
+}
+
+// X5C carries the attestation material for the key that signed a token.
+type X5C struct {
+	// SignatureChain holds the hex-encoded signature chain returned by
+	// GetKey, proving how the signing key was derived.
+	SignatureChain []string `json:"signature_chain,omitempty"`
+	// Quote holds the hex-encoded TDX quote attesting to the CVM that
+	// holds the signing key, if one was supplied to Issue.
+	Quote string `json:"quote,omitempty"`
+}
+
+// Token is a parsed, verified JWS: its header, claims, and raw parts.
+type Token struct {
+	Header Header
+	Claims map[string]interface{}
+}
+
+// Issue signs claims with signer and returns a compact JWS
+// "header.payload.signature". If quote is non-nil, its raw bytes are
+// embedded hex-encoded in the header's X5C.Quote field.
+func Issue(signer *dstack.Signer, claims map[string]interface{}, quote *dstack.GetQuoteResponse) (string, error) {
+	alg, err := algForPublicKey(signer.Public())
+	if err != nil {
+		return "", err
+	}
+
+	header := Header{
+		Alg: alg,
+		Typ: "JWT",
+		X5C: &X5C{SignatureChain: hexEncodeAll(signer.SignatureChain())},
+	}
+	if quote != nil {
+		header.X5C.Quote = hex.EncodeToString(quote.Quote)
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("dstack/jwt: failed to marshal header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("dstack/jwt: failed to marshal claims: %w", err)
+	}
+
+	signingInput := b64(headerJSON) + "." + b64(claimsJSON)
+
+	sig, err := sign(signer, alg, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + b64(sig), nil
+}
+
+// Verify checks a compact JWS produced by Issue against pub and returns its
+// decoded claims. The caller is responsible for independently validating
+// the X5C attestation (e.g. the quote's report_data against pub, and its
+// measurements against a Policy) before trusting the claims.
+func Verify(token string, pub crypto.PublicKey) (*Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("dstack/jwt: malformed token: expected 3 parts, got %d", len(parts))
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("dstack/jwt: failed to decode header: %w", err)
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("dstack/jwt: failed to decode claims: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("dstack/jwt: failed to decode signature: %w", err)
+	}
+
+	var header Header
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("dstack/jwt: failed to unmarshal header: %w", err)
+	}
+
+	wantAlg, err := algForPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if header.Alg != wantAlg {
+		return nil, fmt.Errorf("dstack/jwt: alg %q does not match key type (want %q)", header.Alg, wantAlg)
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	if err := verifySignature(pub, header.Alg, []byte(signingInput), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, fmt.Errorf("dstack/jwt: failed to unmarshal claims: %w", err)
+	}
+
+	return &Token{Header: header, Claims: claims}, nil
+}
+
+// algForPublicKey maps a crypto.PublicKey to the JWS "alg" dstack issues:
+// ES256K for secp256k1, ES256 for P-256, and EdDSA for Ed25519.
+func algForPublicKey(pub crypto.PublicKey) (string, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case ethcrypto.S256():
+			return "ES256K", nil
+		case elliptic.P256():
+			return "ES256", nil
+		default:
+			return "", fmt.Errorf("dstack/jwt: unsupported ECDSA curve %s", key.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("dstack/jwt: unsupported public key type %T", pub)
+	}
+}
+
+// sign produces the JWS signature over signingInput using signer, encoding
+// ECDSA signatures as fixed-width JOSE r||s rather than ASN.1 DER.
+func sign(signer *dstack.Signer, alg string, signingInput []byte) ([]byte, error) {
+	switch alg {
+	case "ES256K", "ES256":
+		digest := sha256.Sum256(signingInput)
+		der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("dstack/jwt: failed to sign: %w", err)
+		}
+		return derToJOSE(der, 32)
+	case "EdDSA":
+		sig, err := signer.Sign(rand.Reader, signingInput, crypto.Hash(0))
+		if err != nil {
+			return nil, fmt.Errorf("dstack/jwt: failed to sign: %w", err)
+		}
+		return sig, nil
+	default:
+		return nil, fmt.Errorf("dstack/jwt: unsupported alg %q", alg)
+	}
+}
+
+// verifySignature checks sig over signingInput using pub, per alg's scheme.
+func verifySignature(pub crypto.PublicKey, alg string, signingInput, sig []byte) error {
+	switch alg {
+	case "ES256K", "ES256":
+		ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("dstack/jwt: alg %q requires an ECDSA public key", alg)
+		}
+		if len(sig) != 64 {
+			return fmt.Errorf("dstack/jwt: unexpected ECDSA signature length: %d", len(sig))
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		digest := sha256.Sum256(signingInput)
+		if !ecdsa.Verify(ecdsaPub, digest[:], r, s) {
+			return fmt.Errorf("dstack/jwt: signature verification failed")
+		}
+		return nil
+	case "EdDSA":
+		edPub, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("dstack/jwt: alg %q requires an Ed25519 public key", alg)
+		}
+		if !ed25519.Verify(edPub, signingInput, sig) {
+			return fmt.Errorf("dstack/jwt: signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("dstack/jwt: unsupported alg %q", alg)
+	}
+}
+
+// derToJOSE converts an ASN.1 DER-encoded ECDSA signature, as produced by
+// crypto.Signer.Sign, into the fixed-width big-endian r||s encoding JOSE
+// requires, with each of r and s padded to size bytes.
+func derToJOSE(der []byte, size int) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, fmt.Errorf("dstack/jwt: failed to parse ECDSA signature: %w", err)
+	}
+
+	out := make([]byte, 2*size)
+	parsed.R.FillBytes(out[:size])
+	parsed.S.FillBytes(out[size:])
+	return out, nil
+}
+
+// b64 base64url-encodes data without padding, as required by JWS.
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hexEncodeAll(chain [][]byte) []string {
+	out := make([]string, len(chain))
+	for i, c := range chain {
+		out[i] = hex.EncodeToString(c)
+	}
+	return out
+}
@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+)
+
+// MeasurementMismatchError reports that a measurement register obtained by
+// replaying an event log does not match the value advertised alongside it.
+type MeasurementMismatchError struct {
+	// Register names the diverging measurement, e.g. "RTMR0" or "MRTD".
+	Register string
+	// Replayed is the hex-encoded value obtained by replaying the event log.
+	Replayed string
+	// Advertised is the hex-encoded value that was supposed to match.
+	Advertised string
+}
+
+func (e *MeasurementMismatchError) Error() string {
+	return fmt.Sprintf("dstack: %s mismatch: replaying the event log gives %s but %s was advertised as %s",
+		e.Register, e.Replayed, e.Register, e.Advertised)
+}
+
+// CollateralProvider validates a TDX quote's PCK certificate chain and
+// returns its TCB status, e.g. by calling Intel PCS, reading an on-disk
+// collateral cache, or shelling out to a local dcap-qvl binary.
+type CollateralProvider interface {
+	VerifyPCKChain(ctx context.Context, quote []byte) (tcbStatus string, err error)
+}
+
+// VerifiedQuote is the result of a successful GetQuoteResponse.Verify call.
+type VerifiedQuote struct {
+	// ReportBody holds the measurements and report data parsed from the
+	// quote.
+	ReportBody *TDXReportBody
+	// ReplayedRTMRs holds the RTMR0-3 values obtained by replaying the
+	// quote's event log; these are asserted to equal ReportBody's RTMRs.
+	ReplayedRTMRs map[int]string
+	// TCBStatus is set if a CollateralProvider was supplied to Verify.
+	TCBStatus string
+}
+
+// verifyOptions holds the optional parameters for GetQuoteResponse.Verify.
+type verifyOptions struct {
+	reportData     []byte
+	reportDataHash QuoteHashAlgorithm
+	collateral     CollateralProvider
+}
+
+// VerifyOption configures GetQuoteResponse.Verify.
+type VerifyOption func(*verifyOptions)
+
+// WithExpectedReportData checks that the quote's report_data equals data,
+// hashed with algorithm first (SHA384, SHA512, or RAW for no hashing) and
+// zero-padded to the report data's length.
+func WithExpectedReportData(data []byte, algorithm QuoteHashAlgorithm) VerifyOption {
+	return func(o *verifyOptions) {
+		o.reportData = data
+		o.reportDataHash = algorithm
+	}
+}
+
+// WithCollateralProvider validates the quote's PCK certificate chain and
+// TCB status using provider.
+func WithCollateralProvider(provider CollateralProvider) VerifyOption {
+	return func(o *verifyOptions) {
+		o.collateral = provider
+	}
+}
+
+// Verify locally checks a quote's internal consistency: it parses MRTD and
+// RTMR0-3 out of the quote body, replays the quote's event log and asserts
+// the result matches, and, if WithExpectedReportData was given, checks the
+// quote's report_data against a caller-supplied value. If
+// WithCollateralProvider was given, it also validates the quote's PCK
+// certificate chain and returns the resulting TCB status.
+//
+// Verify does not itself validate the quote's signature; without a
+// CollateralProvider it only establishes that the quote is internally
+// consistent, not that it was produced by genuine TDX hardware.
+func (r *GetQuoteResponse) Verify(ctx context.Context, opts ...VerifyOption) (*VerifiedQuote, error) {
+	var o verifyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	body, err := ParseTDXQuote(r.Quote)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := r.ReplayRTMRs()
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to replay event log: %w", err)
+	}
+
+	fromQuote := map[int]string{0: body.RTMR0, 1: body.RTMR1, 2: body.RTMR2, 3: body.RTMR3}
+	for idx := 0; idx < 4; idx++ {
+		if replayed[idx] != fromQuote[idx] {
+			return nil, &MeasurementMismatchError{
+				Register:   fmt.Sprintf("RTMR%d", idx),
+				Replayed:   replayed[idx],
+				Advertised: fromQuote[idx],
+			}
+		}
+	}
+
+	if o.reportData != nil {
+		if err := checkReportData(o.reportData, o.reportDataHash, body.ReportData); err != nil {
+			return nil, err
+		}
+	}
+
+	verified := &VerifiedQuote{ReportBody: body, ReplayedRTMRs: replayed}
+
+	if o.collateral != nil {
+		status, err := o.collateral.VerifyPCKChain(ctx, r.Quote)
+		if err != nil {
+			return nil, fmt.Errorf("dstack: PCK certificate chain verification failed: %w", err)
+		}
+		verified.TCBStatus = status
+	}
+
+	return verified, nil
+}
+
+// checkReportData hashes data with algorithm and compares it, zero-padded,
+// against reportData.
+func checkReportData(data []byte, algorithm QuoteHashAlgorithm, reportData []byte) error {
+	var digest []byte
+	switch algorithm {
+	case SHA384:
+		sum := sha512.Sum384(data)
+		digest = sum[:]
+	case SHA512:
+		sum := sha512.Sum512(data)
+		digest = sum[:]
+	case RAW, "":
+		digest = data
+	default:
+		return fmt.Errorf("dstack: unsupported report data hash algorithm %q", algorithm)
+	}
+
+	if len(digest) > len(reportData) {
+		return fmt.Errorf("dstack: hashed report data is longer than the quote's report_data field")
+	}
+
+	want := make([]byte, len(reportData))
+	copy(want, digest)
+	if !bytes.Equal(want, reportData) {
+		return fmt.Errorf("dstack: report_data does not match expected value")
+	}
+	return nil
+}
+
+// VerifiedInfo is the result of a successful InfoResponse.Verify call.
+type VerifiedInfo struct {
+	// TcbInfo is the decoded TcbInfo advertised by InfoResponse.
+	TcbInfo *TcbInfo
+	// ReplayedRTMRs holds the RTMR0-3 values obtained by replaying
+	// TcbInfo's event log; these are asserted to equal TcbInfo's RTMRs.
+	ReplayedRTMRs map[int]string
+}
+
+// Verify decodes r.TcbInfo, replays its event log, and confirms the
+// resulting RTMR0-3 match the values TcbInfo advertises, and that
+// ComposeHash equals sha256(AppCompose).
+func (r *InfoResponse) Verify(_ context.Context) (*VerifiedInfo, error) {
+	tcbInfo, err := r.DecodeTcbInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := replayEventLogRTMRs(tcbInfo.EventLog)
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to replay event log: %w", err)
+	}
+
+	fromTcbInfo := map[int]string{0: tcbInfo.Rtmr0, 1: tcbInfo.Rtmr1, 2: tcbInfo.Rtmr2, 3: tcbInfo.Rtmr3}
+	for idx := 0; idx < 4; idx++ {
+		if replayed[idx] != fromTcbInfo[idx] {
+			return nil, &MeasurementMismatchError{
+				Register:   fmt.Sprintf("RTMR%d", idx),
+				Replayed:   replayed[idx],
+				Advertised: fromTcbInfo[idx],
+			}
+		}
+	}
+
+	if tcbInfo.ComposeHash != "" {
+		sum := sha256.Sum256([]byte(tcbInfo.AppCompose))
+		if got := hex.EncodeToString(sum[:]); got != tcbInfo.ComposeHash {
+			return nil, fmt.Errorf("dstack: compose_hash %q does not match sha256(app_compose) %q", tcbInfo.ComposeHash, got)
+		}
+	}
+
+	return &VerifiedInfo{TcbInfo: tcbInfo, ReplayedRTMRs: replayed}, nil
+}
+
+// replayEventLogRTMRs replays events into RTMR0-3, mirroring
+// GetQuoteResponse.ReplayRTMRs but operating on the richer EventLog entries
+// found in TcbInfo.
+func replayEventLogRTMRs(events []EventLog) (map[int]string, error) {
+	rtmrs := make(map[int]string, 4)
+	for idx := 0; idx < 4; idx++ {
+		history := make([]string, 0)
+		for _, event := range events {
+			if event.IMR == idx {
+				history = append(history, event.Digest)
+			}
+		}
+
+		rtmr, err := replayRTMR(history)
+		if err != nil {
+			return nil, err
+		}
+		rtmrs[idx] = rtmr
+	}
+	return rtmrs, nil
+}
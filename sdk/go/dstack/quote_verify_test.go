@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+// measurementFromLabel deterministically derives a fake 48-byte measurement
+// register value from label, so tests can use readable names instead of
+// hardcoded hex blobs.
+func measurementFromLabel(label string) []byte {
+	sum := sha512.Sum384([]byte(label))
+	return sum[:]
+}
+
+// initMRBytes is the all-zero 48-byte value replayRTMR returns for an empty
+// history (INIT_MR).
+func initMRBytes() []byte {
+	return make([]byte, tdxMeasurementSize)
+}
+
+// buildTDXQuote constructs a minimal raw TDX quote with the given
+// measurement registers and report data placed at the offsets ParseTDXQuote
+// expects.
+func buildTDXQuote(t *testing.T, mrtd, rtmr0, rtmr1, rtmr2, rtmr3, reportData []byte) []byte {
+	t.Helper()
+
+	quote := make([]byte, tdxQuoteHeaderSize+tdxReportBodySize)
+	body := quote[tdxQuoteHeaderSize:]
+
+	put := func(offset, size int, data []byte) {
+		if len(data) != size {
+			t.Fatalf("buildTDXQuote: expected %d bytes, got %d", size, len(data))
+		}
+		copy(body[offset:offset+size], data)
+	}
+
+	put(tdxMRTDOffset, tdxMeasurementSize, mrtd)
+	put(tdxRTMR0Offset, tdxMeasurementSize, rtmr0)
+	put(tdxRTMR1Offset, tdxMeasurementSize, rtmr1)
+	put(tdxRTMR2Offset, tdxMeasurementSize, rtmr2)
+	put(tdxRTMR3Offset, tdxMeasurementSize, rtmr3)
+	put(tdxReportDataOffset, tdxReportDataSize, reportData)
+
+	return quote
+}
+
+// rawEventLogEntry is the {imr, digest} shape GetQuoteResponse.ReplayRTMRs
+// expects in its EventLog field.
+type rawEventLogEntry struct {
+	IMR    int    `json:"imr"`
+	Digest string `json:"digest"`
+}
+
+// eventLogJSON encodes entries as the EventLog field GetQuoteResponse expects.
+func eventLogJSON(t *testing.T, entries ...rawEventLogEntry) string {
+	t.Helper()
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("eventLogJSON: %v", err)
+	}
+	return string(data)
+}
+
+func TestGetQuoteResponseVerify_Success(t *testing.T) {
+	reportData := make([]byte, 64)
+	data := []byte("bound data")
+	sum := sha512.Sum384(data)
+	copy(reportData, sum[:])
+
+	quote := buildTDXQuote(t, measurementFromLabel("mrtd"), initMRBytes(), initMRBytes(), initMRBytes(), initMRBytes(), reportData)
+
+	resp := &GetQuoteResponse{Quote: quote, EventLog: "[]"}
+	verified, err := resp.Verify(context.Background(), WithExpectedReportData(data, SHA384))
+	if err != nil {
+		t.Fatalf("Verify() returned unexpected error: %v", err)
+	}
+	if verified.ReportBody.RTMR0 != hex.EncodeToString(initMRBytes()) {
+		t.Fatalf("unexpected RTMR0 in verified result: %s", verified.ReportBody.RTMR0)
+	}
+}
+
+func TestGetQuoteResponseVerify_MeasurementMismatch(t *testing.T) {
+	tamperedRTMR0 := measurementFromLabel("tampered-rtmr0")
+	quote := buildTDXQuote(t, measurementFromLabel("mrtd"), tamperedRTMR0, initMRBytes(), initMRBytes(), initMRBytes(), make([]byte, 64))
+
+	resp := &GetQuoteResponse{Quote: quote, EventLog: "[]"}
+	_, err := resp.Verify(context.Background())
+	if err == nil {
+		t.Fatalf("Verify() = nil error, want MeasurementMismatchError")
+	}
+	mismatch, ok := err.(*MeasurementMismatchError)
+	if !ok {
+		t.Fatalf("Verify() error = %T, want *MeasurementMismatchError", err)
+	}
+	if mismatch.Register != "RTMR0" {
+		t.Fatalf("mismatch.Register = %q, want RTMR0", mismatch.Register)
+	}
+}
+
+func TestGetQuoteResponseVerify_ReportDataMismatch(t *testing.T) {
+	reportData := make([]byte, 64)
+	sum := sha512.Sum384([]byte("actual data"))
+	copy(reportData, sum[:])
+
+	quote := buildTDXQuote(t, measurementFromLabel("mrtd"), initMRBytes(), initMRBytes(), initMRBytes(), initMRBytes(), reportData)
+
+	resp := &GetQuoteResponse{Quote: quote, EventLog: "[]"}
+	_, err := resp.Verify(context.Background(), WithExpectedReportData([]byte("expected data"), SHA384))
+	if err == nil {
+		t.Fatalf("Verify() = nil error, want a report_data mismatch error")
+	}
+}
+
+func TestInfoResponseVerify_Success(t *testing.T) {
+	appCompose := `{"some":"compose"}`
+	composeSum := sha256.Sum256([]byte(appCompose))
+
+	tcbInfo := TcbInfo{
+		Rtmr0:       hex.EncodeToString(initMRBytes()),
+		Rtmr1:       hex.EncodeToString(initMRBytes()),
+		Rtmr2:       hex.EncodeToString(initMRBytes()),
+		Rtmr3:       hex.EncodeToString(initMRBytes()),
+		AppCompose:  appCompose,
+		ComposeHash: hex.EncodeToString(composeSum[:]),
+	}
+	tcbInfoJSON, err := json.Marshal(tcbInfo)
+	if err != nil {
+		t.Fatalf("json.Marshal(tcbInfo): %v", err)
+	}
+
+	resp := &InfoResponse{TcbInfo: string(tcbInfoJSON)}
+	verified, err := resp.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() returned unexpected error: %v", err)
+	}
+	if verified.TcbInfo.ComposeHash != tcbInfo.ComposeHash {
+		t.Fatalf("verified.TcbInfo.ComposeHash = %q, want %q", verified.TcbInfo.ComposeHash, tcbInfo.ComposeHash)
+	}
+}
+
+func TestInfoResponseVerify_MeasurementMismatch(t *testing.T) {
+	tcbInfo := TcbInfo{
+		Rtmr0: hex.EncodeToString(measurementFromLabel("tampered-rtmr0")),
+		Rtmr1: hex.EncodeToString(initMRBytes()),
+		Rtmr2: hex.EncodeToString(initMRBytes()),
+		Rtmr3: hex.EncodeToString(initMRBytes()),
+	}
+	tcbInfoJSON, err := json.Marshal(tcbInfo)
+	if err != nil {
+		t.Fatalf("json.Marshal(tcbInfo): %v", err)
+	}
+
+	resp := &InfoResponse{TcbInfo: string(tcbInfoJSON)}
+	_, err = resp.Verify(context.Background())
+	if err == nil {
+		t.Fatalf("Verify() = nil error, want MeasurementMismatchError")
+	}
+	if _, ok := err.(*MeasurementMismatchError); !ok {
+		t.Fatalf("Verify() error = %T, want *MeasurementMismatchError", err)
+	}
+}
+
+func TestInfoResponseVerify_ComposeHashMismatch(t *testing.T) {
+	tcbInfo := TcbInfo{
+		Rtmr0:       hex.EncodeToString(initMRBytes()),
+		Rtmr1:       hex.EncodeToString(initMRBytes()),
+		Rtmr2:       hex.EncodeToString(initMRBytes()),
+		Rtmr3:       hex.EncodeToString(initMRBytes()),
+		AppCompose:  `{"some":"compose"}`,
+		ComposeHash: "not-the-real-hash",
+	}
+	tcbInfoJSON, err := json.Marshal(tcbInfo)
+	if err != nil {
+		t.Fatalf("json.Marshal(tcbInfo): %v", err)
+	}
+
+	resp := &InfoResponse{TcbInfo: string(tcbInfoJSON)}
+	_, err = resp.Verify(context.Background())
+	if err == nil {
+		t.Fatalf("Verify() = nil error, want a compose_hash mismatch error")
+	}
+}
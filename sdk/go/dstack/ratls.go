@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"context"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// OIDRATLSQuote is the X.509 certificate extension OID under which dstack
+// embeds attestation material in RA-TLS certificates issued by GetTlsKey
+// with usage_ra_tls set. The extension value is the JSON encoding of
+// raTLSAttestation.
+var OIDRATLSQuote = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 62397, 1, 1}
+
+// raTLSAttestation is the attestation bundle embedded in an RA-TLS
+// certificate's OIDRATLSQuote extension, mirroring the fields a peer would
+// otherwise have to fetch separately via GetQuote and Info.
+type raTLSAttestation struct {
+	Quote       []byte `json:"quote"`
+	EventLog    string `json:"event_log"`
+	OsImageHash string `json:"os_image_hash,omitempty"`
+	ComposeHash string `json:"compose_hash"`
+}
+
+// QuoteVerifier checks the authenticity of a raw TDX quote, e.g. by
+// validating its signature and PCK certificate chain against Intel PCS or a
+// local copy of the collateral (such as via a dcap-qvl binary). A nil
+// QuoteVerifier means quote signatures are not checked, relying solely on
+// measurement comparisons against Policy.
+type QuoteVerifier interface {
+	VerifyQuote(ctx context.Context, quote []byte) error
+}
+
+// Policy describes the measurements a peer's RA-TLS certificate must match
+// to be accepted. Empty allow-lists are not checked. At least one allow-list
+// or Check should be set, otherwise any peer presenting a well-formed,
+// internally-consistent attestation is accepted.
+type Policy struct {
+	// AllowedMRTD, if non-empty, lists the hex-encoded MRTD values accepted.
+	AllowedMRTD []string
+	// AllowedRTMR0-3, if non-empty, list the hex-encoded RTMR values
+	// accepted for the corresponding register.
+	AllowedRTMR0 []string
+	AllowedRTMR1 []string
+	AllowedRTMR2 []string
+	AllowedRTMR3 []string
+	// AllowedOsImageHash and AllowedComposeHash, if non-empty, list the
+	// accepted hex-encoded TcbInfo.OsImageHash / TcbInfo.ComposeHash values.
+	AllowedOsImageHash []string
+	AllowedComposeHash []string
+	// Check, if non-nil, is called with the peer's measurements after the
+	// allow-list checks pass, for arbitrary additional validation.
+	Check func(TcbInfo) error
+}
+
+// RATLSVerifier validates a peer's RA-TLS certificate during a TLS
+// handshake: it checks the quote's signature via QuoteVerifier, checks that
+// the quote's report_data binds the certificate's public key, replays the
+// embedded event log to confirm RTMR0-3 weren't tampered with, and
+// evaluates Policy against the resulting measurements.
+type RATLSVerifier struct {
+	Policy        Policy
+	QuoteVerifier QuoteVerifier
+
+	// allowUnverifiedQuote is set by NewInsecureRATLSVerifier to permit
+	// QuoteVerifier being nil, i.e. accepting a quote's measurements without
+	// checking that the quote itself was signed by genuine TDX hardware.
+	allowUnverifiedQuote bool
+}
+
+// RATLSOption configures a RATLSVerifier.
+type RATLSOption func(*RATLSVerifier)
+
+// WithQuoteVerifier sets the QuoteVerifier used to check quote signatures
+// and PCK certificate chains.
+func WithQuoteVerifier(qv QuoteVerifier) RATLSOption {
+	return func(v *RATLSVerifier) {
+		v.QuoteVerifier = qv
+	}
+}
+
+// NewRATLSVerifier creates a RATLSVerifier enforcing the given Policy,
+// using quoteVerifier to check that a peer's quote was actually signed by
+// genuine TDX hardware. quoteVerifier must be non-nil: without it, a peer
+// could present a self-signed certificate with fabricated measurements and
+// pass every other check here. Use NewInsecureRATLSVerifier if you
+// deliberately want to skip quote signature verification, e.g. in tests.
+func NewRATLSVerifier(policy Policy, quoteVerifier QuoteVerifier, opts ...RATLSOption) (*RATLSVerifier, error) {
+	if quoteVerifier == nil {
+		return nil, fmt.Errorf("dstack: QuoteVerifier must not be nil; use NewInsecureRATLSVerifier to explicitly accept unverified quotes")
+	}
+	v := &RATLSVerifier{Policy: policy, QuoteVerifier: quoteVerifier}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v, nil
+}
+
+// NewInsecureRATLSVerifier creates a RATLSVerifier that does not check a
+// peer quote's signature, relying solely on measurement comparisons against
+// Policy. This accepts a self-signed certificate with fabricated
+// measurements from anyone who can compute a SHA-384 hash, and should only
+// be used in tests or alongside some other channel of trust.
+func NewInsecureRATLSVerifier(policy Policy, opts ...RATLSOption) *RATLSVerifier {
+	v := &RATLSVerifier{Policy: policy, allowUnverifiedQuote: true}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// VerifyPeerCertificate is suitable for use as tls.Config.VerifyPeerCertificate.
+// It must be paired with tls.Config.InsecureSkipVerify = true, since RA-TLS
+// certificates are self-issued and trust instead comes from the attested
+// quote checked here.
+func (v *RATLSVerifier) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if v.QuoteVerifier == nil && !v.allowUnverifiedQuote {
+		return fmt.Errorf("dstack: RATLSVerifier has no QuoteVerifier configured; construct it with NewRATLSVerifier or, to explicitly accept unverified quotes, NewInsecureRATLSVerifier")
+	}
+
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("dstack: no peer certificate presented")
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("dstack: failed to parse peer certificate: %w", err)
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("dstack: peer certificate is not valid at %s (NotBefore=%s, NotAfter=%s)", now, cert.NotBefore, cert.NotAfter)
+	}
+
+	attestation, err := extractAttestation(cert)
+	if err != nil {
+		return err
+	}
+
+	if v.QuoteVerifier != nil {
+		if err := v.QuoteVerifier.VerifyQuote(context.Background(), attestation.Quote); err != nil {
+			return fmt.Errorf("dstack: quote verification failed: %w", err)
+		}
+	}
+
+	body, err := ParseTDXQuote(attestation.Quote)
+	if err != nil {
+		return fmt.Errorf("dstack: failed to parse peer quote: %w", err)
+	}
+
+	if err := verifyReportDataBinding(cert, body.ReportData); err != nil {
+		return err
+	}
+
+	replayed, err := (&GetQuoteResponse{EventLog: attestation.EventLog}).ReplayRTMRs()
+	if err != nil {
+		return fmt.Errorf("dstack: failed to replay peer event log: %w", err)
+	}
+	if err := compareReplayedRTMRs(replayed, body); err != nil {
+		return err
+	}
+
+	return v.Policy.evaluate(body, attestation)
+}
+
+// extractAttestation decodes the raTLSAttestation embedded in cert's
+// OIDRATLSQuote extension.
+func extractAttestation(cert *x509.Certificate) (*raTLSAttestation, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(OIDRATLSQuote) {
+			continue
+		}
+		var a raTLSAttestation
+		if err := json.Unmarshal(ext.Value, &a); err != nil {
+			return nil, fmt.Errorf("dstack: failed to decode RA-TLS attestation extension: %w", err)
+		}
+		return &a, nil
+	}
+	return nil, fmt.Errorf("dstack: peer certificate has no RA-TLS quote extension")
+}
+
+// verifyReportDataBinding checks that the quote's report_data commits to
+// cert's public key, as SHA-384(SubjectPublicKeyInfo DER) zero-padded to the
+// report data's 64 bytes.
+func verifyReportDataBinding(cert *x509.Certificate, reportData []byte) error {
+	digest := sha512.Sum384(cert.RawSubjectPublicKeyInfo)
+
+	want := make([]byte, len(reportData))
+	copy(want, digest[:])
+
+	if len(reportData) != len(want) {
+		return fmt.Errorf("dstack: unexpected report_data length: got %d", len(reportData))
+	}
+	for i := range want {
+		if reportData[i] != want[i] {
+			return fmt.Errorf("dstack: report_data does not bind peer's public key")
+		}
+	}
+	return nil
+}
+
+// compareReplayedRTMRs checks that the RTMRs obtained by replaying the
+// attestation's event log match the RTMRs embedded in the quote itself,
+// catching a quote whose body was tampered with independently of its log.
+func compareReplayedRTMRs(replayed map[int]string, body *TDXReportBody) error {
+	fromQuote := map[int]string{0: body.RTMR0, 1: body.RTMR1, 2: body.RTMR2, 3: body.RTMR3}
+	for idx, want := range fromQuote {
+		if replayed[idx] != want {
+			return fmt.Errorf("dstack: replayed RTMR%d %q does not match quote RTMR%d %q", idx, replayed[idx], idx, want)
+		}
+	}
+	return nil
+}
+
+// evaluate checks body's measurements and the attestation's OS image/compose
+// hashes against the Policy's allow-lists and Check callback.
+func (p Policy) evaluate(body *TDXReportBody, attestation *raTLSAttestation) error {
+	if !allowed(p.AllowedMRTD, body.MRTD) {
+		return fmt.Errorf("dstack: MRTD %q not in policy allow-list", body.MRTD)
+	}
+	if !allowed(p.AllowedRTMR0, body.RTMR0) {
+		return fmt.Errorf("dstack: RTMR0 %q not in policy allow-list", body.RTMR0)
+	}
+	if !allowed(p.AllowedRTMR1, body.RTMR1) {
+		return fmt.Errorf("dstack: RTMR1 %q not in policy allow-list", body.RTMR1)
+	}
+	if !allowed(p.AllowedRTMR2, body.RTMR2) {
+		return fmt.Errorf("dstack: RTMR2 %q not in policy allow-list", body.RTMR2)
+	}
+	if !allowed(p.AllowedRTMR3, body.RTMR3) {
+		return fmt.Errorf("dstack: RTMR3 %q not in policy allow-list", body.RTMR3)
+	}
+	if !allowed(p.AllowedOsImageHash, attestation.OsImageHash) {
+		return fmt.Errorf("dstack: os_image_hash %q not in policy allow-list", attestation.OsImageHash)
+	}
+	if !allowed(p.AllowedComposeHash, attestation.ComposeHash) {
+		return fmt.Errorf("dstack: compose_hash %q not in policy allow-list", attestation.ComposeHash)
+	}
+
+	if p.Check == nil {
+		return nil
+	}
+	return p.Check(TcbInfo{
+		Mrtd:        body.MRTD,
+		Rtmr0:       body.RTMR0,
+		Rtmr1:       body.RTMR1,
+		Rtmr2:       body.RTMR2,
+		Rtmr3:       body.RTMR3,
+		OsImageHash: attestation.OsImageHash,
+		ComposeHash: attestation.ComposeHash,
+	})
+}
+
+// allowed reports whether value is in list, or list is empty (unchecked).
+func allowed(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRATLSTransport returns an *http.Transport that validates peer
+// certificates using verifier instead of the system trust store, so that
+// http.Client requests to another CVM's RA-TLS listener are attested
+// end-to-end. If base is nil, http.DefaultTransport.Clone() is used.
+func NewRATLSTransport(verifier *RATLSVerifier, base *http.Transport) *http.Transport {
+	var transport *http.Transport
+	if base != nil {
+		transport = base.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifier.VerifyPeerCertificate,
+	}
+	return transport
+}
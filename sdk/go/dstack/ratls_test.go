@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// ratlsTestCertParams collects the knobs the table-driven
+// TestVerifyPeerCertificate cases vary.
+type ratlsTestCertParams struct {
+	notBefore        time.Time
+	notAfter         time.Time
+	tamperReportData bool
+	tamperQuoteRTMR0 bool
+	composeHash      string
+}
+
+// buildRATLSTestCert generates a fresh key pair and a self-signed certificate
+// whose OIDRATLSQuote extension embeds a synthetic quote and event log, in
+// the same shape RATLSVerifier.VerifyPeerCertificate expects from a real
+// RA-TLS certificate.
+func buildRATLSTestCert(t *testing.T, p ratlsTestCertParams) []byte {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey: %v", err)
+	}
+
+	spki, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKIXPublicKey: %v", err)
+	}
+
+	reportData := make([]byte, 64)
+	if p.tamperReportData {
+		sum := sha512.Sum384([]byte("not the certificate's key"))
+		copy(reportData, sum[:])
+	} else {
+		sum := sha512.Sum384(spki)
+		copy(reportData, sum[:])
+	}
+
+	// The event log replays (via replayRTMR's hash chaining) to rtmr0Replayed;
+	// the quote itself advertises rtmr0InQuote, which matches unless the test
+	// wants the two to disagree (a quote tampered independently of its log).
+	eventDigest := hex.EncodeToString(measurementFromLabel("event-log-rtmr0"))
+	rtmr0Replayed, err := replayRTMR([]string{eventDigest})
+	if err != nil {
+		t.Fatalf("replayRTMR: %v", err)
+	}
+	rtmr0InQuote := rtmr0Replayed
+	if p.tamperQuoteRTMR0 {
+		rtmr0InQuote = hex.EncodeToString(measurementFromLabel("quote-rtmr0-does-not-match-log"))
+	}
+
+	quote := buildTDXQuote(t, measurementFromLabel("mrtd"), mustHexDecode(t, rtmr0InQuote), initMRBytes(), initMRBytes(), initMRBytes(), reportData)
+	eventLog := eventLogJSON(t, rawEventLogEntry{IMR: 0, Digest: eventDigest})
+
+	composeHash := p.composeHash
+	if composeHash == "" {
+		composeHash = "test-compose-hash"
+	}
+	attestation := raTLSAttestation{Quote: quote, EventLog: eventLog, ComposeHash: composeHash}
+	extValue, err := json.Marshal(attestation)
+	if err != nil {
+		t.Fatalf("json.Marshal(attestation): %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "ratls-test"},
+		NotBefore:       p.notBefore,
+		NotAfter:        p.notAfter,
+		ExtraExtensions: []pkix.Extension{{Id: OIDRATLSQuote, Value: extValue}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate: %v", err)
+	}
+	return der
+}
+
+// mustHexDecode decodes a hex string, failing the test on error.
+func mustHexDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("hex.DecodeString(%q): %v", s, err)
+	}
+	return b
+}
+
+func validRATLSTestCertParams() ratlsTestCertParams {
+	now := time.Now()
+	return ratlsTestCertParams{
+		notBefore:   now.Add(-time.Hour),
+		notAfter:    now.Add(time.Hour),
+		composeHash: "test-compose-hash",
+	}
+}
+
+func TestVerifyPeerCertificate_Success(t *testing.T) {
+	cert := buildRATLSTestCert(t, validRATLSTestCertParams())
+	verifier := NewInsecureRATLSVerifier(Policy{AllowedComposeHash: []string{"test-compose-hash"}})
+
+	if err := verifier.VerifyPeerCertificate([][]byte{cert}, nil); err != nil {
+		t.Fatalf("VerifyPeerCertificate() returned unexpected error: %v", err)
+	}
+}
+
+func TestVerifyPeerCertificate_TamperedRTMR(t *testing.T) {
+	params := validRATLSTestCertParams()
+	params.tamperQuoteRTMR0 = true
+	cert := buildRATLSTestCert(t, params)
+	verifier := NewInsecureRATLSVerifier(Policy{})
+
+	err := verifier.VerifyPeerCertificate([][]byte{cert}, nil)
+	if err == nil {
+		t.Fatalf("VerifyPeerCertificate() = nil error, want a replayed-RTMR mismatch error")
+	}
+}
+
+func TestVerifyPeerCertificate_ReportDataNotBound(t *testing.T) {
+	params := validRATLSTestCertParams()
+	params.tamperReportData = true
+	cert := buildRATLSTestCert(t, params)
+	verifier := NewInsecureRATLSVerifier(Policy{})
+
+	err := verifier.VerifyPeerCertificate([][]byte{cert}, nil)
+	if err == nil {
+		t.Fatalf("VerifyPeerCertificate() = nil error, want a report_data binding error")
+	}
+}
+
+func TestVerifyPeerCertificate_ExpiredCert(t *testing.T) {
+	params := validRATLSTestCertParams()
+	params.notBefore = time.Now().Add(-2 * time.Hour)
+	params.notAfter = time.Now().Add(-time.Hour)
+	cert := buildRATLSTestCert(t, params)
+	verifier := NewInsecureRATLSVerifier(Policy{})
+
+	err := verifier.VerifyPeerCertificate([][]byte{cert}, nil)
+	if err == nil {
+		t.Fatalf("VerifyPeerCertificate() = nil error, want an expired-certificate error")
+	}
+}
+
+func TestVerifyPeerCertificate_PolicyAllowListHit(t *testing.T) {
+	cert := buildRATLSTestCert(t, validRATLSTestCertParams())
+	verifier := NewInsecureRATLSVerifier(Policy{AllowedComposeHash: []string{"some-other-hash", "test-compose-hash"}})
+
+	if err := verifier.VerifyPeerCertificate([][]byte{cert}, nil); err != nil {
+		t.Fatalf("VerifyPeerCertificate() returned unexpected error: %v", err)
+	}
+}
+
+func TestVerifyPeerCertificate_PolicyAllowListMiss(t *testing.T) {
+	cert := buildRATLSTestCert(t, validRATLSTestCertParams())
+	verifier := NewInsecureRATLSVerifier(Policy{AllowedComposeHash: []string{"some-other-hash"}})
+
+	err := verifier.VerifyPeerCertificate([][]byte{cert}, nil)
+	if err == nil {
+		t.Fatalf("VerifyPeerCertificate() = nil error, want a policy allow-list rejection")
+	}
+}
+
+func TestVerifyPeerCertificate_NoQuoteVerifierRejected(t *testing.T) {
+	cert := buildRATLSTestCert(t, validRATLSTestCertParams())
+	verifier := &RATLSVerifier{Policy: Policy{}}
+
+	err := verifier.VerifyPeerCertificate([][]byte{cert}, nil)
+	if err == nil {
+		t.Fatalf("VerifyPeerCertificate() = nil error, want a rejection for a verifier with no QuoteVerifier configured")
+	}
+}
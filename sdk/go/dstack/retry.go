@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures sendRPCRequest's backoff and circuit-breaker
+// behavior.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the initial
+	// attempt.
+	MaxRetries int
+	// InitialInterval is the backoff before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the backoff between retries.
+	MaxInterval time.Duration
+	// Multiplier scales the backoff interval after each retry.
+	Multiplier float64
+	// MaxElapsedTime bounds the total time spent retrying a single call,
+	// measured from the first attempt.
+	MaxElapsedTime time.Duration
+
+	// BreakerThreshold is the number of consecutive failures on an
+	// endpoint after which the circuit breaker opens and fails fast.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before allowing
+	// a trial request through again.
+	BreakerCooldown time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when WithRetryPolicy is not
+// given: up to 4 retries with backoff starting at 200ms, doubling up to
+// 5s, bounded by a 30s total budget, and a breaker that opens after 5
+// consecutive failures on an endpoint for 10s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:       4,
+		InitialInterval:  200 * time.Millisecond,
+		MaxInterval:      5 * time.Second,
+		Multiplier:       2,
+		MaxElapsedTime:   30 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  10 * time.Second,
+	}
+}
+
+// withJitter returns d plus up to 50% random jitter, to avoid retry storms
+// across multiple clients backing off in lockstep.
+func withJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// circuitBreaker fails fast for an endpoint once it has seen threshold
+// consecutive errors, until cooldown has elapsed since the last failure.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu            sync.Mutex
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+}
+
+// newCircuitBreaker creates a circuitBreaker. A non-positive threshold
+// disables the breaker (Allow always returns true).
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a request should be attempted. It returns false if
+// the breaker is open and either cooldown hasn't elapsed yet or a single
+// half-open trial request is already in flight; only the caller that flips
+// trialInFlight is let through until that trial's outcome is recorded via
+// RecordSuccess or RecordFailure.
+func (b *circuitBreaker) Allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trialInFlight {
+		return false
+	}
+	if time.Since(b.openedAt) >= b.cooldown {
+		b.trialInFlight = true
+		return true
+	}
+	return false
+}
+
+// RecordSuccess resets the breaker's failure count and clears any in-flight
+// half-open trial.
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trialInFlight = false
+}
+
+// RecordFailure increments the breaker's failure count, (re-)opening it
+// once threshold is reached, and clears any in-flight half-open trial so a
+// later Allow call can start a new one after cooldown.
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+	b.trialInFlight = false
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker(3, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatalf("Allow() = false before threshold reached")
+		}
+		b.RecordFailure()
+	}
+	if !b.Allow() {
+		t.Fatalf("Allow() = false on the request that reaches threshold")
+	}
+	b.RecordFailure()
+
+	if b.Allow() {
+		t.Fatalf("Allow() = true once the breaker is open")
+	}
+}
+
+func TestCircuitBreakerHalfOpenSingleFlight(t *testing.T) {
+	b := newCircuitBreaker(1, time.Millisecond)
+	b.RecordFailure()
+
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("Allow() = false for the first caller after cooldown elapsed")
+	}
+	if b.Allow() {
+		t.Fatalf("Allow() = true for a second caller while the trial request is in flight")
+	}
+
+	b.RecordFailure()
+
+	if b.Allow() {
+		t.Fatalf("Allow() = true immediately after the trial failed, before cooldown elapsed again")
+	}
+}
+
+func TestCircuitBreakerClosesOnSuccess(t *testing.T) {
+	b := newCircuitBreaker(1, time.Millisecond)
+	b.RecordFailure()
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("Allow() = false for the trial request")
+	}
+	b.RecordSuccess()
+
+	if !b.Allow() {
+		t.Fatalf("Allow() = false after the trial request succeeded")
+	}
+}
+
+func TestCircuitBreakerDisabledWithoutThreshold(t *testing.T) {
+	b := newCircuitBreaker(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		b.RecordFailure()
+	}
+	if !b.Allow() {
+		t.Fatalf("Allow() = false for a breaker with a non-positive threshold")
+	}
+}
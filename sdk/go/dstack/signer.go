@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeyAlgorithm identifies the asymmetric algorithm of a key derived through
+// GetKey or used to Sign.
+type KeyAlgorithm string
+
+const (
+	// AlgorithmSecp256k1 is the ECDSA/secp256k1 algorithm used by Ethereum.
+	AlgorithmSecp256k1 KeyAlgorithm = "secp256k1"
+	// AlgorithmP256 is ECDSA over the NIST P-256 curve.
+	AlgorithmP256 KeyAlgorithm = "p256"
+	// AlgorithmEd25519 is the Ed25519 signature algorithm.
+	AlgorithmEd25519 KeyAlgorithm = "ed25519"
+)
+
+// Signer wraps DstackClient.Sign to implement crypto.Signer, so a dstack key
+// can be plugged directly into APIs such as x509.CreateCertificate or
+// tls.Certificate.PrivateKey without exposing the private key material,
+// which never leaves the dstack KMS.
+type Signer struct {
+	client    *DstackClient
+	algorithm KeyAlgorithm
+	path      string
+	purpose   string
+
+	public         crypto.PublicKey
+	signatureChain [][]byte
+}
+
+// NewSigner derives the key at path for purpose using algorithm and returns
+// a Signer for it. The derived public key is fetched once via GetKey and
+// cached; Sign performs a remote /Sign RPC for every signature.
+func NewSigner(ctx context.Context, client *DstackClient, algorithm KeyAlgorithm, path string, purpose string) (*Signer, error) {
+	resp, err := client.GetKey(ctx, path, purpose, string(algorithm))
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to get key for signer: %w", err)
+	}
+
+	keyBytes, err := hex.DecodeString(resp.Key)
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to decode key material: %w", err)
+	}
+
+	pub, err := parsePublicKey(algorithm, keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([][]byte, len(resp.SignatureChain))
+	for i, s := range resp.SignatureChain {
+		chain[i], err = hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("dstack: failed to decode signature chain element %d: %w", i, err)
+		}
+	}
+
+	return &Signer{
+		client:         client,
+		algorithm:      algorithm,
+		path:           path,
+		purpose:        purpose,
+		public:         pub,
+		signatureChain: chain,
+	}, nil
+}
+
+// parsePublicKey decodes the raw key bytes returned by GetKey into the
+// crypto.PublicKey type appropriate for algorithm.
+func parsePublicKey(algorithm KeyAlgorithm, keyBytes []byte) (crypto.PublicKey, error) {
+	switch algorithm {
+	case AlgorithmSecp256k1:
+		pub, err := ethcrypto.UnmarshalPubkey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("dstack: failed to parse secp256k1 public key: %w", err)
+		}
+		return pub, nil
+	case AlgorithmP256:
+		x, y := elliptic.Unmarshal(elliptic.P256(), keyBytes)
+		if x == nil {
+			return nil, fmt.Errorf("dstack: failed to parse P-256 public key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case AlgorithmEd25519:
+		if len(keyBytes) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("dstack: unexpected ed25519 public key length: %d", len(keyBytes))
+		}
+		return ed25519.PublicKey(keyBytes), nil
+	default:
+		return nil, fmt.Errorf("dstack: unsupported key algorithm %q", algorithm)
+	}
+}
+
+// Public returns the signer's public key, as derived during NewSigner.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// SignatureChain returns the chain of signatures attesting to how this
+// key was derived, as returned by GetKey.
+func (s *Signer) SignatureChain() [][]byte {
+	return s.signatureChain
+}
+
+// Sign implements crypto.Signer. It ignores rand, since signing happens
+// remotely inside the dstack KMS, and validates that digest's length
+// matches opts.HashFunc() before dispatching a /Sign RPC with
+// context.Background(), as the crypto.Signer interface has no room for a
+// caller-supplied context.
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if h := opts.HashFunc(); h != crypto.Hash(0) {
+		if !h.Available() {
+			return nil, fmt.Errorf("dstack: unsupported hash function %v", h)
+		}
+		if len(digest) != h.Size() {
+			return nil, fmt.Errorf("dstack: digest length %d does not match hash function %v", len(digest), h)
+		}
+	}
+
+	resp, err := s.client.Sign(context.Background(), string(s.algorithm), digest)
+	if err != nil {
+		return nil, fmt.Errorf("dstack: failed to sign digest: %w", err)
+	}
+	return resp.Signature, nil
+}
+
+var _ crypto.Signer = (*Signer)(nil)
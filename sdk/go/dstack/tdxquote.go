@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: © 2025 Phala Network <dstack@phala.network>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dstack
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// tdxQuoteHeaderSize is the size in bytes of the fixed TDX quote header that
+// precedes the TD report body.
+const tdxQuoteHeaderSize = 48
+
+// tdxReportBodySize is the size in bytes of the TDX 1.0 TD report body.
+const tdxReportBodySize = 584
+
+// tdxMeasurementSize is the size in bytes of a single TDX measurement
+// register (MRTD or RTMRn), which is a SHA-384 digest.
+const tdxMeasurementSize = 48
+
+// Offsets within the TD report body, per the TDX 1.0 report format. mrtd is
+// followed by mrconfigid/mrowner/mrownerconfig (unused here) before the four
+// RTMRs and the report data.
+const (
+	tdxMRTDOffset       = 136
+	tdxRTMR0Offset      = 328
+	tdxRTMR1Offset      = tdxRTMR0Offset + tdxMeasurementSize
+	tdxRTMR2Offset      = tdxRTMR1Offset + tdxMeasurementSize
+	tdxRTMR3Offset      = tdxRTMR2Offset + tdxMeasurementSize
+	tdxReportDataOffset = tdxRTMR3Offset + tdxMeasurementSize
+	tdxReportDataSize   = 64
+)
+
+// TDXReportBody holds the measurement registers and report data extracted
+// from the body of a TDX quote.
+type TDXReportBody struct {
+	MRTD       string
+	RTMR0      string
+	RTMR1      string
+	RTMR2      string
+	RTMR3      string
+	ReportData []byte
+}
+
+// ParseTDXQuote extracts the TD report body (MRTD, RTMR0-3, report_data)
+// from the raw bytes of a TDX ECDSA quote, as returned in
+// GetQuoteResponse.Quote. It does not verify the quote's signature or PCK
+// certificate chain; use a QuoteVerifier for that.
+func ParseTDXQuote(quote []byte) (*TDXReportBody, error) {
+	if len(quote) < tdxQuoteHeaderSize+tdxReportBodySize {
+		return nil, fmt.Errorf("dstack: quote too short to contain a TD report body: got %d bytes", len(quote))
+	}
+
+	body := quote[tdxQuoteHeaderSize : tdxQuoteHeaderSize+tdxReportBodySize]
+
+	return &TDXReportBody{
+		MRTD:       hex.EncodeToString(body[tdxMRTDOffset : tdxMRTDOffset+tdxMeasurementSize]),
+		RTMR0:      hex.EncodeToString(body[tdxRTMR0Offset : tdxRTMR0Offset+tdxMeasurementSize]),
+		RTMR1:      hex.EncodeToString(body[tdxRTMR1Offset : tdxRTMR1Offset+tdxMeasurementSize]),
+		RTMR2:      hex.EncodeToString(body[tdxRTMR2Offset : tdxRTMR2Offset+tdxMeasurementSize]),
+		RTMR3:      hex.EncodeToString(body[tdxRTMR3Offset : tdxRTMR3Offset+tdxMeasurementSize]),
+		ReportData: append([]byte(nil), body[tdxReportDataOffset:tdxReportDataOffset+tdxReportDataSize]...),
+	}, nil
+}